@@ -0,0 +1,229 @@
+/* Text analysis pipeline used by full-text indexes: CharFilter -> Tokenizer -> TokenFilters. */
+package db
+
+import (
+	"strings"
+	"unicode"
+)
+
+// CharFilter pre-processes raw text before tokenization (e.g. stripping markup).
+type CharFilter interface {
+	Filter(input string) string
+}
+
+// Tokenizer splits filtered text into a sequence of raw tokens.
+type Tokenizer interface {
+	Tokenize(input string) []string
+}
+
+// TokenFilter transforms, drops or expands tokens produced by a Tokenizer.
+type TokenFilter interface {
+	Filter(tokens []string) []string
+}
+
+// Analyzer is a named CharFilter -> Tokenizer -> TokenFilters chain that turns text into index terms.
+type Analyzer struct {
+	Name         string
+	CharFilters  []CharFilter
+	Tokenizer    Tokenizer
+	TokenFilters []TokenFilter
+}
+
+// Analyze runs the full pipeline and returns the resulting terms.
+func (a *Analyzer) Analyze(text string) []string {
+	for _, cf := range a.CharFilters {
+		text = cf.Filter(text)
+	}
+	tokens := a.Tokenizer.Tokenize(text)
+	for _, tf := range a.TokenFilters {
+		tokens = tf.Filter(tokens)
+	}
+	return tokens
+}
+
+// UnicodeTokenizer splits on Unicode word boundaries (runs of letters/digits).
+type UnicodeTokenizer struct{}
+
+func (UnicodeTokenizer) Tokenize(input string) []string {
+	var tokens []string
+	var cur []rune
+	flush := func() {
+		if len(cur) > 0 {
+			tokens = append(tokens, string(cur))
+			cur = cur[:0]
+		}
+	}
+	for _, r := range input {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			cur = append(cur, r)
+		} else {
+			flush()
+		}
+	}
+	flush()
+	return tokens
+}
+
+// LowerCaseFilter lower-cases every token.
+type LowerCaseFilter struct{}
+
+func (LowerCaseFilter) Filter(tokens []string) []string {
+	out := make([]string, len(tokens))
+	for i, t := range tokens {
+		out[i] = strings.ToLower(t)
+	}
+	return out
+}
+
+// ASCIIFoldingFilter strips combining diacritical marks so "café" matches "cafe".
+type ASCIIFoldingFilter struct{}
+
+func (ASCIIFoldingFilter) Filter(tokens []string) []string {
+	out := make([]string, len(tokens))
+	for i, t := range tokens {
+		folded := make([]rune, 0, len(t))
+		for _, r := range norm(t) {
+			if unicode.Is(unicode.Mn, r) {
+				continue // combining mark, drop it
+			}
+			folded = append(folded, r)
+		}
+		out[i] = string(folded)
+	}
+	return out
+}
+
+// norm decomposes a string into runes, relying on Go's default NFC input; a best-effort fold for
+// the common Latin-1 accented letters is applied since we don't pull in golang.org/x/text here.
+func norm(s string) []rune {
+	replacer := strings.NewReplacer(
+		"á", "a", "à", "a", "â", "a", "ä", "a", "ã", "a", "å", "a",
+		"é", "e", "è", "e", "ê", "e", "ë", "e",
+		"í", "i", "ì", "i", "î", "i", "ï", "i",
+		"ó", "o", "ò", "o", "ô", "o", "ö", "o", "õ", "o",
+		"ú", "u", "ù", "u", "û", "u", "ü", "u",
+		"ñ", "n", "ç", "c",
+	)
+	return []rune(replacer.Replace(s))
+}
+
+// StopWordFilter removes a fixed list of common English stop words.
+type StopWordFilter struct {
+	stop map[string]struct{}
+}
+
+// NewEnglishStopWordFilter returns a StopWordFilter pre-loaded with common English stop words.
+func NewEnglishStopWordFilter() *StopWordFilter {
+	words := []string{"a", "an", "and", "are", "as", "at", "be", "but", "by",
+		"for", "if", "in", "into", "is", "it", "no", "not", "of", "on",
+		"or", "such", "that", "the", "their", "then", "there", "these",
+		"they", "this", "to", "was", "will", "with"}
+	stop := make(map[string]struct{}, len(words))
+	for _, w := range words {
+		stop[w] = struct{}{}
+	}
+	return &StopWordFilter{stop: stop}
+}
+
+func (f *StopWordFilter) Filter(tokens []string) []string {
+	out := tokens[:0]
+	for _, t := range tokens {
+		if _, isStop := f.stop[t]; !isStop {
+			out = append(out, t)
+		}
+	}
+	return out
+}
+
+// SnowballStemFilter applies a small Porter-style suffix-stripping stemmer, close enough to
+// snowball's English algorithm for index/query term matching without pulling in a dependency.
+type SnowballStemFilter struct{}
+
+var stemSuffixes = []struct {
+	suffix      string
+	replacement string
+	minStemLen  int
+}{
+	{"sses", "ss", 1},
+	{"ies", "y", 1},
+	{"ing", "", 2},
+	{"edly", "", 2},
+	{"ed", "", 2},
+	{"ational", "ate", 2},
+	{"ization", "ize", 2},
+	{"fulness", "ful", 2},
+	{"ness", "", 2},
+	{"ment", "", 2},
+	{"es", "e", 1},
+	{"s", "", 2},
+}
+
+func (SnowballStemFilter) Filter(tokens []string) []string {
+	out := make([]string, len(tokens))
+	for i, t := range tokens {
+		out[i] = stem(t)
+	}
+	return out
+}
+
+func stem(word string) string {
+	for _, rule := range stemSuffixes {
+		if strings.HasSuffix(word, rule.suffix) {
+			stemmed := strings.TrimSuffix(word, rule.suffix) + rule.replacement
+			if len(stemmed) >= rule.minStemLen {
+				return stemmed
+			}
+		}
+	}
+	return word
+}
+
+// NewStandardAnalyzer returns the default analyzer: Unicode tokenizer, lower-casing, ASCII
+// folding, English stop word removal and snowball-style stemming.
+func NewStandardAnalyzer() *Analyzer {
+	return &Analyzer{
+		Name:      "standard",
+		Tokenizer: UnicodeTokenizer{},
+		TokenFilters: []TokenFilter{
+			LowerCaseFilter{},
+			ASCIIFoldingFilter{},
+			NewEnglishStopWordFilter(),
+			SnowballStemFilter{},
+		},
+	}
+}
+
+// NewKeywordAnalyzer returns an analyzer that only lower-cases, treating the whole input as one
+// token; useful for exact-match fields that still need case-insensitive full-text search.
+func NewKeywordAnalyzer() *Analyzer {
+	return &Analyzer{
+		Name:         "keyword",
+		Tokenizer:    keywordTokenizer{},
+		TokenFilters: []TokenFilter{LowerCaseFilter{}},
+	}
+}
+
+type keywordTokenizer struct{}
+
+func (keywordTokenizer) Tokenize(input string) []string {
+	if input == "" {
+		return nil
+	}
+	return []string{input}
+}
+
+// analyzers is the registry of analyzers known by name, looked up by Col.IndexText.
+var analyzers = map[string]*Analyzer{
+	"standard": NewStandardAnalyzer(),
+	"keyword":  NewKeywordAnalyzer(),
+}
+
+// GetAnalyzer returns a registered analyzer by name, or nil if none is registered under it.
+func GetAnalyzer(name string) *Analyzer {
+	return analyzers[name]
+}
+
+// RegisterAnalyzer makes a custom analyzer available to Col.IndexText by name.
+func RegisterAnalyzer(name string, a *Analyzer) {
+	analyzers[name] = a
+}
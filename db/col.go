@@ -0,0 +1,212 @@
+/* Collection: documents plus every index (hashtable, text, ordered, trigram) kept over them. */
+package db
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
+)
+
+// Col is an opened collection: documents plus whatever indexes have been built over them.
+type Col struct {
+	db   *DB
+	name string
+
+	idLock sync.Mutex
+	nextID int
+	parts  []map[int][]byte // encoded (and possibly compressed) document bytes, keyed by doc ID
+
+	indexPaths map[string][]string // joint path -> path, same keys as hts
+	hts        []map[string]*HashTable
+
+	codec Codec
+
+	textIndexes    map[string][]*TextIndex // joint path -> one TextIndex per partition
+	textIndexPaths map[string][]string
+
+	orderedIndexes    map[string][]*OrderedIndex // joint path -> one OrderedIndex per partition
+	orderedIndexPaths map[string][]string
+
+	trigramIndexes    map[string][]*TrigramIndex // joint path -> one TrigramIndex per partition
+	trigramIndexPaths map[string][]string
+}
+
+func newCol(db *DB, name string) *Col {
+	col := &Col{
+		db:                db,
+		name:              name,
+		parts:             make([]map[int][]byte, db.numParts),
+		indexPaths:        make(map[string][]string),
+		hts:               make([]map[string]*HashTable, db.numParts),
+		textIndexes:       make(map[string][]*TextIndex),
+		textIndexPaths:    make(map[string][]string),
+		orderedIndexes:    make(map[string][]*OrderedIndex),
+		orderedIndexPaths: make(map[string][]string),
+		trigramIndexes:    make(map[string][]*TrigramIndex),
+		trigramIndexPaths: make(map[string][]string),
+	}
+	for i := 0; i < db.numParts; i++ {
+		col.parts[i] = make(map[int][]byte)
+		col.hts[i] = make(map[string]*HashTable)
+	}
+	return col
+}
+
+func (col *Col) partOf(id int) int {
+	n := id % len(col.parts)
+	if n < 0 {
+		n += len(col.parts)
+	}
+	return n
+}
+
+// ApproxDocCount estimates the number of documents in the collection.
+func (col *Col) ApproxDocCount() int {
+	total := 0
+	for _, part := range col.parts {
+		total += len(part)
+	}
+	return total
+}
+
+// ForEachDoc iterates every document's JSON bytes, decoded from their codec header by DecodeDoc;
+// documents that fail to decode are skipped. fun returning false stops the iteration early.
+func (col *Col) ForEachDoc(fun func(id int, data []byte) bool) {
+	for _, part := range col.parts {
+		for id, stored := range part {
+			data, err := DecodeDoc(stored)
+			if err != nil {
+				continue
+			}
+			if !fun(id, data) {
+				return
+			}
+		}
+	}
+}
+
+// Read fetches and decodes a document by ID.
+func (col *Col) Read(id int) (doc map[string]interface{}, err error) {
+	stored, exists := col.parts[col.partOf(id)][id]
+	if !exists {
+		return nil, fmt.Errorf("document %d does not exist", id)
+	}
+	data, err := DecodeDoc(stored)
+	if err != nil {
+		return nil, err
+	}
+	if err = json.Unmarshal(data, &doc); err != nil {
+		return nil, err
+	}
+	return doc, nil
+}
+
+// Index builds a hashtable index over path, backfilling every existing document.
+func (col *Col) Index(path []string) error {
+	jointPath := strings.Join(path, INDEX_PATH_SEP)
+	col.indexPaths[jointPath] = path
+	for i := range col.hts {
+		col.hts[i][jointPath] = NewHashTable()
+	}
+	col.ForEachDoc(func(id int, _ []byte) bool {
+		if doc, err := col.Read(id); err == nil {
+			col.putHashIndex(id, jointPath, path, doc)
+		}
+		return true
+	})
+	return nil
+}
+
+func (col *Col) putHashIndex(id int, jointPath string, path []string, doc map[string]interface{}) {
+	for _, v := range GetIn(doc, path) {
+		hash := StrHash(fmt.Sprint(v))
+		ht := col.hts[hash%len(col.hts)][jointPath]
+		ht.Lock.Lock()
+		ht.Put(hash, id)
+		ht.Lock.Unlock()
+	}
+}
+
+func (col *Col) removeHashIndexes(id int, doc map[string]interface{}) {
+	for jointPath, path := range col.indexPaths {
+		for _, v := range GetIn(doc, path) {
+			hash := StrHash(fmt.Sprint(v))
+			ht := col.hts[hash%len(col.hts)][jointPath]
+			ht.Lock.Lock()
+			ht.Remove(hash, id)
+			ht.Lock.Unlock()
+		}
+	}
+}
+
+// indexDoc adds doc (just inserted/updated under id) into every index maintained over the
+// collection: hashtable, full-text, ordered and trigram.
+func (col *Col) indexDoc(id int, doc map[string]interface{}) {
+	for jointPath, path := range col.indexPaths {
+		col.putHashIndex(id, jointPath, path, doc)
+	}
+	col.indexTextDoc(id, doc)
+	col.putOrderedDoc(id, doc)
+	col.addTrigramDoc(id, doc)
+}
+
+// deindexDoc removes doc (about to be updated or deleted) from every index maintained over the
+// collection, the mirror image of indexDoc.
+func (col *Col) deindexDoc(id int, doc map[string]interface{}) {
+	col.removeHashIndexes(id, doc)
+	col.removeTextDoc(id, doc)
+	col.removeOrderedDoc(id, doc)
+	col.removeTrigramDoc(id, doc)
+}
+
+// Insert adds a new document, returning its allocated ID, and updates every index maintained over
+// the collection.
+func (col *Col) Insert(doc map[string]interface{}) (id int, err error) {
+	data, err := json.Marshal(doc)
+	if err != nil {
+		return 0, err
+	}
+	col.idLock.Lock()
+	id = col.nextID
+	col.nextID++
+	col.idLock.Unlock()
+	col.parts[col.partOf(id)][id] = col.EncodeDoc(data)
+	col.indexDoc(id, doc)
+	return id, nil
+}
+
+// Update replaces the document stored under id, removing its old index entries before adding the
+// new ones so every index reflects the new content.
+func (col *Col) Update(id int, doc map[string]interface{}) (err error) {
+	oldDoc, err := col.Read(id)
+	if err != nil {
+		return err
+	}
+	data, err := json.Marshal(doc)
+	if err != nil {
+		return err
+	}
+	col.deindexDoc(id, oldDoc)
+	col.parts[col.partOf(id)][id] = col.EncodeDoc(data)
+	col.indexDoc(id, doc)
+	return nil
+}
+
+// Delete removes the document stored under id from the collection and every index over it.
+func (col *Col) Delete(id int) (err error) {
+	doc, err := col.Read(id)
+	if err != nil {
+		return err
+	}
+	col.deindexDoc(id, doc)
+	delete(col.parts[col.partOf(id)], id)
+	return nil
+}
+
+// saveConfig persists collection-level configuration (currently just the compression codec).
+// There is no on-disk config file in this in-memory collection implementation, so there is
+// nothing to flush; SetCompression already keeps col.codec as the single source of truth.
+func (col *Col) saveConfig() error {
+	return nil
+}
@@ -0,0 +1,86 @@
+/* Transparent document compression: documents are stored with a 1-byte codec header so that old,
+uncompressed databases stay readable while new codecs can be added without a migration step. */
+package db
+
+import (
+	"fmt"
+
+	"github.com/golang/snappy"
+)
+
+// Codec identifies how a document's bytes are encoded on disk; it is always the first byte of a
+// document record, read by Read/ForEachDoc and written by Insert/Update.
+type Codec byte
+
+const (
+	CodecRaw    Codec = 0 // stored verbatim, as every document was before compression existed
+	CodecSnappy Codec = 1
+)
+
+// codecNames maps the config string accepted by Col.SetCompression to its on-disk Codec.
+var codecNames = map[string]Codec{
+	"none":   CodecRaw,
+	"snappy": CodecSnappy,
+}
+
+// SetCompression changes the codec used for documents inserted or updated from now on. The choice
+// is kept in col.codec only: saveConfig writes no config file, so it lasts as long as the
+// in-memory collection does. Existing documents are left exactly as they were written - run
+// Col.Recompress to rewrite them under the new codec.
+func (col *Col) SetCompression(name string) error {
+	codec, known := codecNames[name]
+	if !known {
+		return fmt.Errorf("unknown compression codec %s", name)
+	}
+	col.codec = codec
+	return col.saveConfig()
+}
+
+// EncodeDoc prepends the collection's current codec header and compresses the payload if needed.
+// Insert and Update must run every document through this before writing it to the data file.
+func (col *Col) EncodeDoc(data []byte) []byte {
+	switch col.codec {
+	case CodecSnappy:
+		compressed := snappy.Encode(nil, data)
+		return append([]byte{byte(CodecSnappy)}, compressed...)
+	default:
+		return append([]byte{byte(CodecRaw)}, data...)
+	}
+}
+
+// DecodeDoc strips the codec header and decompresses the payload accordingly. Read and
+// ForEachDoc must run every document they load through this before handing it to the caller.
+func DecodeDoc(stored []byte) ([]byte, error) {
+	if len(stored) == 0 {
+		return stored, nil
+	}
+	header, payload := Codec(stored[0]), stored[1:]
+	switch header {
+	case CodecRaw:
+		return payload, nil
+	case CodecSnappy:
+		return snappy.Decode(nil, payload)
+	default:
+		return nil, fmt.Errorf("unknown document codec byte %d, database may be corrupted", header)
+	}
+}
+
+// Recompress rewrites every document in the collection under the current codec; run it after
+// SetCompression to shrink (or restore) the working set of an existing collection.
+func (col *Col) Recompress() error {
+	ids := make([]int, 0)
+	col.ForEachDoc(func(id int, _ []byte) bool {
+		ids = append(ids, id)
+		return true
+	})
+	for _, id := range ids {
+		doc, err := col.Read(id)
+		if err != nil {
+			continue // document was deleted or corrupted concurrently, skip it
+		}
+		if err := col.Update(id, doc); err != nil {
+			return fmt.Errorf("failed to recompress document %d: %v", id, err)
+		}
+	}
+	return nil
+}
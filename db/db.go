@@ -0,0 +1,28 @@
+/* Database handle: owns collections and the partition count indexes are split across. */
+package db
+
+// numPartsDefault is the number of partitions each collection's indexes are split into, matching
+// the partition counts used throughout query.go and the benchmark.
+const numPartsDefault = 4
+
+// DB represents an opened tiedot database directory.
+type DB struct {
+	path     string
+	numParts int
+	cols     map[string]*Col
+}
+
+// OpenDB returns an empty in-memory database; path is recorded but no directory is read or created.
+func OpenDB(path string) (*DB, error) {
+	return &DB{path: path, numParts: numPartsDefault, cols: make(map[string]*Col)}, nil
+}
+
+// OpenCol opens (creating if necessary) a collection inside db.
+func OpenCol(db *DB, name string) (*Col, error) {
+	if col, exists := db.cols[name]; exists {
+		return col, nil
+	}
+	col := newCol(db, name)
+	db.cols[name] = col
+	return col, nil
+}
@@ -0,0 +1,61 @@
+/* In-memory hashtable backing Col.Index: one instance per (partition, indexed path). */
+package db
+
+import "sync"
+
+// HashTable maps a hash value to the sorted-by-insertion doc IDs stored under it. Callers are
+// responsible for taking Lock themselves around Get/GetPartition/Put/Remove, matching how
+// query.go already guards its own hashtable access.
+type HashTable struct {
+	Lock    sync.RWMutex
+	buckets map[int][]int
+}
+
+// NewHashTable creates an empty hashtable.
+func NewHashTable() *HashTable {
+	return &HashTable{buckets: make(map[int][]int)}
+}
+
+// Put records that key hashes to docID.
+func (ht *HashTable) Put(key, docID int) {
+	ht.buckets[key] = append(ht.buckets[key], docID)
+}
+
+// Remove deletes docID from under key.
+func (ht *HashTable) Remove(key, docID int) {
+	ids := ht.buckets[key]
+	for i, id := range ids {
+		if id == docID {
+			ht.buckets[key] = append(ids[:i], ids[i+1:]...)
+			break
+		}
+	}
+}
+
+// Get returns up to limit doc IDs stored under key (limit <= 0 means unlimited).
+func (ht *HashTable) Get(key, limit int) []int {
+	ids := ht.buckets[key]
+	if limit > 0 && len(ids) > limit {
+		return ids[:limit]
+	}
+	return ids
+}
+
+// GetPartition returns the doc IDs belonging to the i-th of partDiv roughly-equal slices of
+// buckets, letting PathExistence collect IDs a few thousand at a time instead of all at once.
+func (ht *HashTable) GetPartition(i, partDiv int) (n int, ids []int) {
+	for key, bucket := range ht.buckets {
+		if partDiv <= 1 || key%partDiv == i {
+			ids = append(ids, bucket...)
+			n++
+		}
+	}
+	return
+}
+
+// ApproxBucketCount estimates how many doc IDs are stored under key, used by the query planner.
+func (ht *HashTable) ApproxBucketCount(key int) int {
+	ht.Lock.RLock()
+	defer ht.Lock.RUnlock()
+	return len(ht.buckets[key])
+}
@@ -0,0 +1,246 @@
+/* Ordered index: a sorted-key structure for range queries that hash indexes cannot answer. */
+package db
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// OrderedIndex keeps (key -> doc IDs) pairs sorted by key, one per partition, so that arbitrary
+// "between A and B" range scans can run without enumerating every possible key like IntRange does.
+// The in-memory representation is a flat sorted slice rather than a true B+tree: tiedot's
+// partitions are small enough that binary search over a sorted slice gives range scans their
+// O(log n + k) cost without the complexity of a disk-backed tree structure.
+type OrderedIndex struct {
+	Lock    sync.RWMutex
+	entries []orderedEntry // kept sorted by key
+}
+
+type orderedEntry struct {
+	key string
+	ids []int
+}
+
+// NewOrderedIndex creates an empty ordered index.
+func NewOrderedIndex() *OrderedIndex {
+	return &OrderedIndex{}
+}
+
+func (oi *OrderedIndex) search(key string) (idx int, found bool) {
+	idx = sort.Search(len(oi.entries), func(i int) bool { return oi.entries[i].key >= key })
+	found = idx < len(oi.entries) && oi.entries[idx].key == key
+	return
+}
+
+// Put adds docID under key, keeping entries sorted.
+func (oi *OrderedIndex) Put(key string, docID int) {
+	oi.Lock.Lock()
+	defer oi.Lock.Unlock()
+	idx, found := oi.search(key)
+	if found {
+		oi.entries[idx].ids = append(oi.entries[idx].ids, docID)
+		return
+	}
+	oi.entries = append(oi.entries, orderedEntry{})
+	copy(oi.entries[idx+1:], oi.entries[idx:])
+	oi.entries[idx] = orderedEntry{key: key, ids: []int{docID}}
+}
+
+// Remove deletes docID from under key.
+func (oi *OrderedIndex) Remove(key string, docID int) {
+	oi.Lock.Lock()
+	defer oi.Lock.Unlock()
+	idx, found := oi.search(key)
+	if !found {
+		return
+	}
+	ids := oi.entries[idx].ids
+	for i, id := range ids {
+		if id == docID {
+			ids = append(ids[:i], ids[i+1:]...)
+			break
+		}
+	}
+	if len(ids) == 0 {
+		oi.entries = append(oi.entries[:idx], oi.entries[idx+1:]...)
+	} else {
+		oi.entries[idx].ids = ids
+	}
+}
+
+// Range streams (key, doc ID) pairs for keys within [from, to] (or [to, from] if to < from),
+// honoring inclusive/exclusive bounds, in ascending or descending key order, until limit IDs are
+// emitted (limit <= 0 means unlimited). It never materializes more than one key's doc IDs at a
+// time. The key is handed to emit alongside the ID so callers merging several partitions' streams
+// (StrRange) can restore global key order instead of trusting each partition's own local order.
+func (oi *OrderedIndex) Range(from, to string, inclusiveFrom, inclusiveTo bool, descending bool, limit int, emit func(key string, id int) (more bool)) {
+	oi.Lock.RLock()
+	defer oi.Lock.RUnlock()
+	lo, hi := from, to
+	if hi < lo {
+		lo, hi = hi, lo
+	}
+	start := sort.Search(len(oi.entries), func(i int) bool { return oi.entries[i].key >= lo })
+	end := sort.Search(len(oi.entries), func(i int) bool { return oi.entries[i].key > hi })
+	matched := oi.entries[start:end]
+	if !inclusiveFrom && len(matched) > 0 && matched[0].key == lo {
+		matched = matched[1:]
+	}
+	if !inclusiveTo && len(matched) > 0 && matched[len(matched)-1].key == hi {
+		matched = matched[:len(matched)-1]
+	}
+	emitted := 0
+	visit := func(e orderedEntry) bool {
+		for _, id := range e.ids {
+			if limit > 0 && emitted >= limit {
+				return false
+			}
+			if !emit(e.key, id) {
+				return false
+			}
+			emitted++
+		}
+		return true
+	}
+	if descending {
+		for i := len(matched) - 1; i >= 0; i-- {
+			if !visit(matched[i]) {
+				return
+			}
+		}
+	} else {
+		for i := range matched {
+			if !visit(matched[i]) {
+				return
+			}
+		}
+	}
+}
+
+// IndexOrdered builds an ordered index over `path`, exposing range queries ("from"/"to" or the
+// string-typed "str-from"/"str-to" aliases) that EvalQuery's Intersect/Complement can combine with
+// hash-indexed lookups like any other set of IDs. Like hts and the text/trigram indexes, each
+// path gets its own independent set of per-partition OrderedIndex instances, added to
+// col.orderedIndexes rather than replacing it, so indexing a second path leaves the first path's
+// index (and its postings) untouched.
+func (col *Col) IndexOrdered(path []string) error {
+	jointPath := strings.Join(path, INDEX_PATH_SEP)
+	perPartition := make([]*OrderedIndex, col.db.numParts)
+	for i := range perPartition {
+		perPartition[i] = NewOrderedIndex()
+	}
+	col.orderedIndexes[jointPath] = perPartition
+	col.orderedIndexPaths[jointPath] = path
+	col.ForEachDoc(func(id int, _ []byte) bool {
+		if doc, err := col.Read(id); err == nil {
+			col.putOrderedPath(id, jointPath, path, doc)
+		}
+		return true
+	})
+	return nil
+}
+
+func (col *Col) putOrderedPath(id int, jointPath string, path []string, doc map[string]interface{}) {
+	for _, v := range GetIn(doc, path) {
+		key := fmt.Sprint(v)
+		col.orderedIndexes[jointPath][StrHash(key)%col.db.numParts].Put(key, id)
+	}
+}
+
+// putOrderedDoc adds a freshly inserted/updated document's values into every ordered index, each
+// keyed by its own path; Insert and Update call this, Delete calls removeOrderedDoc beforehand.
+func (col *Col) putOrderedDoc(id int, doc map[string]interface{}) {
+	for jointPath, path := range col.orderedIndexPaths {
+		col.putOrderedPath(id, jointPath, path, doc)
+	}
+}
+
+// removeOrderedDoc removes a document from every ordered index ahead of Update/Delete.
+func (col *Col) removeOrderedDoc(id int, doc map[string]interface{}) {
+	for jointPath, path := range col.orderedIndexPaths {
+		for _, v := range GetIn(doc, path) {
+			key := fmt.Sprint(v)
+			col.orderedIndexes[jointPath][StrHash(key)%col.db.numParts].Remove(key, id)
+		}
+	}
+}
+
+// rangeHit is one matching (key, doc ID) pair collected from a single partition's Range call,
+// kept around just long enough for StrRange to merge every partition's hits into global key order.
+type rangeHit struct {
+	key string
+	id  int
+}
+
+// StrRange implements the "str-from"/"str-to" (and generic "from"/"to") range operator, scanning
+// an ordered index honoring `limit`, `order` ("asc"|"desc", default "asc") and `inclusive` (default
+// true). Partitions are assigned by key hash, not by key order, so a partition being visited first
+// says nothing about which partition holds the overall lowest/highest keys; every partition's
+// matches are merged and sorted by key (mirroring how TextSearch merges per-partition BM25 scores)
+// before `limit` is applied, so the result is always the true global top/bottom `limit` keys.
+func StrRange(fromValue interface{}, expr map[string]interface{}, src *Col, result *map[int]struct{}) (err error) {
+	path, hasPath := expr["in"]
+	if !hasPath {
+		return errors.New("missing path `in`")
+	}
+	vecPath := make([]string, 0)
+	if vecPathInterface, ok := path.([]interface{}); ok {
+		for _, v := range vecPathInterface {
+			vecPath = append(vecPath, fmt.Sprint(v))
+		}
+	} else {
+		return fmt.Errorf("expecting vector path `in`, but %v given", path)
+	}
+	jointPath := strings.Join(vecPath, INDEX_PATH_SEP)
+	partitions, ordered := src.orderedIndexes[jointPath]
+	if !ordered {
+		return fmt.Errorf("%v is not ordered-indexed, call Col.IndexOrdered first (query %v)", vecPath, expr)
+	}
+	toValue, hasTo := expr["str-to"]
+	if !hasTo {
+		toValue, hasTo = expr["to"]
+	}
+	if !hasTo {
+		return errors.New("missing `str-to`/`to`")
+	}
+	from, to := fmt.Sprint(fromValue), fmt.Sprint(toValue)
+	inclusive := true
+	if v, ok := expr["inclusive"].(bool); ok {
+		inclusive = v
+	}
+	descending := false
+	if order, ok := expr["order"].(string); ok && order == "desc" {
+		descending = true
+	}
+	intLimit := 0
+	if limit, hasLimit := expr["limit"]; hasLimit {
+		floatLimit, ok := limit.(float64)
+		if !ok {
+			return fmt.Errorf("expecting `limit` as a number, but %v given", limit)
+		}
+		intLimit = int(floatLimit)
+	}
+	var hits []rangeHit
+	for _, oi := range partitions {
+		oi.Range(from, to, inclusive, inclusive, descending, 0, func(key string, id int) bool {
+			hits = append(hits, rangeHit{key: key, id: id})
+			return true
+		})
+	}
+	sort.Slice(hits, func(i, j int) bool {
+		if descending {
+			return hits[i].key > hits[j].key
+		}
+		return hits[i].key < hits[j].key
+	})
+	if intLimit > 0 && len(hits) > intLimit {
+		hits = hits[:intLimit]
+	}
+	for _, h := range hits {
+		(*result)[h.id] = struct{}{}
+	}
+	return nil
+}
@@ -0,0 +1,79 @@
+package db
+
+import "testing"
+
+func TestStrRangeScopedByPath(t *testing.T) {
+	tmpDB, _ := OpenDB("")
+	col, _ := OpenCol(tmpDB, "test")
+	col.IndexOrdered([]string{"name"})
+	col.IndexOrdered([]string{"city"})
+
+	idByName, _ := col.Insert(map[string]interface{}{"name": "bob", "city": "zzz"})
+	idByCity, _ := col.Insert(map[string]interface{}{"name": "zzz", "city": "bob"})
+
+	result := make(map[int]struct{})
+	q := map[string]interface{}{"str-from": "alice", "str-to": "charlie", "in": []interface{}{"name"}}
+	if err := EvalQuery(q, col, &result); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := result[idByName]; !ok {
+		t.Errorf("expected document %d (matches via name) in result %v", idByName, result)
+	}
+	if _, ok := result[idByCity]; ok {
+		t.Errorf("document %d only falls in range via city, must not appear when querying name", idByCity)
+	}
+}
+
+func TestStrRangeLimitIsGlobal(t *testing.T) {
+	tmpDB, _ := OpenDB("")
+	col, _ := OpenCol(tmpDB, "test")
+	col.IndexOrdered([]string{"name"})
+
+	names := []string{"aaa", "bbb", "ccc", "ddd", "eee", "fff", "ggg", "hhh"}
+	for _, n := range names {
+		if _, err := col.Insert(map[string]interface{}{"name": n}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	result := make(map[int]struct{})
+	q := map[string]interface{}{"str-from": "aaa", "str-to": "zzz", "in": []interface{}{"name"}, "limit": float64(2)}
+	if err := EvalQuery(q, col, &result); err != nil {
+		t.Fatal(err)
+	}
+	if len(result) > 2 {
+		t.Errorf("expected at most 2 results under a global limit, got %d: %v", len(result), result)
+	}
+}
+
+func TestStrRangeLimitPicksTrueGlobalExtremum(t *testing.T) {
+	tmpDB, _ := OpenDB("")
+	col, _ := OpenCol(tmpDB, "test")
+	col.IndexOrdered([]string{"name"})
+
+	// Keys land in partitions by hash, not by key order, so whichever partition StrRange happens
+	// to visit first must not determine the answer: with order:"desc", limit:1, the single result
+	// must always be "hhh" (the true maximum), however the keys happen to be spread across
+	// partitions.
+	names := []string{"aaa", "bbb", "ccc", "ddd", "eee", "fff", "ggg", "hhh"}
+	ids := make(map[string]int, len(names))
+	for _, n := range names {
+		id, err := col.Insert(map[string]interface{}{"name": n})
+		if err != nil {
+			t.Fatal(err)
+		}
+		ids[n] = id
+	}
+
+	result := make(map[int]struct{})
+	q := map[string]interface{}{"str-from": "aaa", "str-to": "zzz", "in": []interface{}{"name"}, "order": "desc", "limit": float64(1)}
+	if err := EvalQuery(q, col, &result); err != nil {
+		t.Fatal(err)
+	}
+	if len(result) != 1 {
+		t.Fatalf("expected exactly one result, got %d: %v", len(result), result)
+	}
+	if _, ok := result[ids["hhh"]]; !ok {
+		t.Errorf("expected the true maximum key's document %d in result %v, desc order+limit must not return an arbitrary per-partition extremum", ids["hhh"], result)
+	}
+}
@@ -0,0 +1,84 @@
+/* Parameterized queries: substitute named placeholders into a query tree before evaluating it. */
+package db
+
+import (
+	"fmt"
+	"strings"
+)
+
+// ErrMissingParam is returned by EvalQueryWithParams when the query references a parameter name
+// that is absent from the supplied params map.
+type ErrMissingParam struct {
+	Name string
+}
+
+func (e ErrMissingParam) Error() string {
+	return fmt.Sprintf("query references parameter %q, which is missing from params", e.Name)
+}
+
+// paramRef, if non-empty, is the parameter name referenced by a query value: either the string
+// "$name" or the single-key object {"param": "name"}.
+func paramRef(v interface{}) string {
+	switch val := v.(type) {
+	case string:
+		if strings.HasPrefix(val, "$") && len(val) > 1 {
+			return val[1:]
+		}
+	case map[string]interface{}:
+		if len(val) == 1 {
+			if name, ok := val["param"].(string); ok {
+				return name
+			}
+		}
+	}
+	return ""
+}
+
+// substitute walks a query tree, replacing every parameter reference with its value from params.
+// Values are substituted wherever a literal appears today: as an `eq` value, inside `in` path
+// arrays, and as `int-from`/`int-to`/`limit` numerics (and their string-typed siblings).
+func substitute(q interface{}, params map[string]interface{}) (interface{}, error) {
+	if name := paramRef(q); name != "" {
+		value, has := params[name]
+		if !has {
+			return nil, ErrMissingParam{Name: name}
+		}
+		return value, nil
+	}
+	switch expr := q.(type) {
+	case []interface{}:
+		out := make([]interface{}, len(expr))
+		for i, sub := range expr {
+			substituted, err := substitute(sub, params)
+			if err != nil {
+				return nil, err
+			}
+			out[i] = substituted
+		}
+		return out, nil
+	case map[string]interface{}:
+		out := make(map[string]interface{}, len(expr))
+		for k, v := range expr {
+			substituted, err := substitute(v, params)
+			if err != nil {
+				return nil, err
+			}
+			out[k] = substituted
+		}
+		return out, nil
+	default:
+		return q, nil
+	}
+}
+
+// EvalQueryWithParams resolves every `{"param": "name"}` / `"$name"` placeholder in q against
+// params, then evaluates the resulting query exactly like EvalQuery. It lets callers build a
+// query once and reuse it with different parameter values, e.g. from an HTTP handler, without
+// string-splicing JSON to fill in user-supplied values.
+func EvalQueryWithParams(q interface{}, params map[string]interface{}, src *Col, result *map[int]struct{}) error {
+	resolved, err := substitute(q, params)
+	if err != nil {
+		return err
+	}
+	return EvalQuery(resolved, src, result)
+}
@@ -0,0 +1,139 @@
+package db
+
+import "testing"
+
+func TestEvalQueryWithParamsSubstitutesDollarAndParamForms(t *testing.T) {
+	tmpDB, _ := OpenDB("")
+	col, _ := OpenCol(tmpDB, "test")
+	col.Index([]string{"tag"})
+
+	match, _ := col.Insert(map[string]interface{}{"tag": "wanted"})
+	col.Insert(map[string]interface{}{"tag": "noise"})
+
+	params := map[string]interface{}{"tagValue": "wanted"}
+
+	result := make(map[int]struct{})
+	q := map[string]interface{}{"eq": "$tagValue", "in": []interface{}{"tag"}}
+	if err := EvalQueryWithParams(q, params, col, &result); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := result[match]; !ok || len(result) != 1 {
+		t.Errorf("expected exactly document %d via \"$name\" substitution, got %v", match, result)
+	}
+
+	result = make(map[int]struct{})
+	q = map[string]interface{}{"eq": map[string]interface{}{"param": "tagValue"}, "in": []interface{}{"tag"}}
+	if err := EvalQueryWithParams(q, params, col, &result); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := result[match]; !ok || len(result) != 1 {
+		t.Errorf("expected exactly document %d via {\"param\":\"name\"} substitution, got %v", match, result)
+	}
+}
+
+func TestEvalQueryWithParamsMissingParam(t *testing.T) {
+	tmpDB, _ := OpenDB("")
+	col, _ := OpenCol(tmpDB, "test")
+	col.Index([]string{"tag"})
+	col.Insert(map[string]interface{}{"tag": "wanted"})
+
+	result := make(map[int]struct{})
+	q := map[string]interface{}{"eq": "$missing", "in": []interface{}{"tag"}}
+	err := EvalQueryWithParams(q, map[string]interface{}{}, col, &result)
+	if err == nil {
+		t.Fatal("expected ErrMissingParam, got nil error")
+	}
+	missing, ok := err.(ErrMissingParam)
+	if !ok {
+		t.Fatalf("expected ErrMissingParam, got %T: %v", err, err)
+	}
+	if missing.Name != "missing" {
+		t.Errorf("expected missing param name %q, got %q", "missing", missing.Name)
+	}
+}
+
+func TestEvalQueryWithParamsNestedInIntersectAndComplement(t *testing.T) {
+	tmpDB, _ := OpenDB("")
+	col, _ := OpenCol(tmpDB, "test")
+	col.Index([]string{"tag"})
+	col.Index([]string{"rank"})
+
+	match, _ := col.Insert(map[string]interface{}{"tag": "wanted", "rank": 1})
+	col.Insert(map[string]interface{}{"tag": "wanted", "rank": 2})
+	col.Insert(map[string]interface{}{"tag": "other", "rank": 1})
+
+	params := map[string]interface{}{"tagValue": "wanted", "rankValue": float64(1)}
+
+	result := make(map[int]struct{})
+	q := map[string]interface{}{"n": []interface{}{
+		map[string]interface{}{"eq": "$tagValue", "in": []interface{}{"tag"}},
+		map[string]interface{}{"eq": "$rankValue", "in": []interface{}{"rank"}},
+	}}
+	if err := EvalQueryWithParams(q, params, col, &result); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := result[match]; !ok || len(result) != 1 {
+		t.Errorf("expected exactly document %d from a parameterized \"n\", got %v", match, result)
+	}
+
+	result = make(map[int]struct{})
+	qc := map[string]interface{}{"c": []interface{}{
+		"all",
+		map[string]interface{}{"eq": "$tagValue", "in": []interface{}{"tag"}},
+	}}
+	if err := EvalQueryWithParams(qc, params, col, &result); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := result[match]; ok {
+		t.Errorf("document %d matches \"$tagValue\" and must not appear in its complement, got %v", match, result)
+	}
+}
+
+func TestEvalQueryWithParamsReusesSameValueEverywhereReferenced(t *testing.T) {
+	tmpDB, _ := OpenDB("")
+	col, _ := OpenCol(tmpDB, "test")
+	col.Index([]string{"a"})
+	col.Index([]string{"b"})
+
+	// Both fields must equal the value of "$shared" for a document to match, so both references
+	// in the query have to be substituted.
+	match, _ := col.Insert(map[string]interface{}{"a": "x", "b": "x"})
+	col.Insert(map[string]interface{}{"a": "x", "b": "y"})
+
+	params := map[string]interface{}{"shared": "x"}
+	result := make(map[int]struct{})
+	q := map[string]interface{}{"n": []interface{}{
+		map[string]interface{}{"eq": "$shared", "in": []interface{}{"a"}},
+		map[string]interface{}{"eq": "$shared", "in": []interface{}{"b"}},
+	}}
+	if err := EvalQueryWithParams(q, params, col, &result); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := result[match]; !ok || len(result) != 1 {
+		t.Errorf("expected exactly document %d, got %v", match, result)
+	}
+}
+
+func TestEvalQueryWithParamsTreatsDollarPathElementAsParam(t *testing.T) {
+	tmpDB, _ := OpenDB("")
+	col, _ := OpenCol(tmpDB, "test")
+	col.Index([]string{"$special"})
+
+	match, _ := col.Insert(map[string]interface{}{"$special": "value"})
+
+	// A path element that happens to start with "$" is indistinguishable from a parameter
+	// reference by paramRef, so it must be supplied in params like any other placeholder.
+	result := make(map[int]struct{})
+	q := map[string]interface{}{"eq": "value", "in": []interface{}{"$special"}}
+	if err := EvalQueryWithParams(q, map[string]interface{}{}, col, &result); err == nil {
+		t.Errorf("expected path element \"$special\" to be treated as a parameter reference and fail to resolve against an empty params map, got result %v", result)
+	}
+
+	result = make(map[int]struct{})
+	if err := EvalQueryWithParams(q, map[string]interface{}{"special": "$special"}, col, &result); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := result[match]; !ok || len(result) != 1 {
+		t.Errorf("expected exactly document %d once \"special\" resolves the path element, got %v", match, result)
+	}
+}
@@ -0,0 +1,177 @@
+/* Cost-based planning for Intersect/Complement: cheap sub-queries run first. In Intersect, expensive
+ones then only have to narrow an already-small working set instead of being fully materialized up
+front; Complement still evaluates every sub-query in full, only in the planned order. */
+package db
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// EstimateCardinality guesses how many document IDs a sub-query will produce, without actually
+// evaluating it, so Intersect/Complement can decide which sub-query to run first. Estimates are
+// deliberately cheap and approximate - being roughly right is enough to pick a good evaluation
+// order.
+func EstimateCardinality(q interface{}, src *Col) int {
+	switch expr := q.(type) {
+	case []interface{}: // union - each sub-query contributes its own IDs
+		sum := 0
+		for _, sub := range expr {
+			sum += EstimateCardinality(sub, src)
+		}
+		return sum
+	case string:
+		if expr == "all" {
+			return src.ApproxDocCount()
+		}
+		return 1 // a single document PK ID
+	case map[string]interface{}:
+		if lookupValue, lookup := expr["eq"]; lookup {
+			return estimateLookup(lookupValue, expr, src)
+		} else if _, exist := expr["has"]; exist {
+			return src.ApproxDocCount() // PathExistence touches every indexed document
+		} else if subExprs, intersect := expr["n"]; intersect {
+			return estimateIntersect(subExprs, src)
+		} else if subExprs, complement := expr["c"]; complement {
+			return estimateUnion(subExprs, src) // complement narrows a union-sized candidate set
+		} else if intFrom, htRange := expr["int-from"]; htRange {
+			return estimateIntRange(intFrom, expr, src)
+		} else if intFrom, htRange := expr["int from"]; htRange {
+			return estimateIntRange(intFrom, expr, src)
+		} else if _, search := expr["search"]; search {
+			return estimateLimited(expr, src)
+		} else if _, strRange := expr["str-from"]; strRange {
+			return estimateLimited(expr, src)
+		} else if _, strRange := expr["from"]; strRange {
+			return estimateLimited(expr, src)
+		} else if _, regex := expr["re"]; regex {
+			return estimateLimited(expr, src)
+		}
+	}
+	return src.ApproxDocCount()
+}
+
+func estimateLookup(lookupValue interface{}, expr map[string]interface{}, src *Col) int {
+	path, ok := expr["in"].([]interface{})
+	if !ok {
+		return src.ApproxDocCount()
+	}
+	vecPath := make([]string, 0, len(path))
+	for _, v := range path {
+		vecPath = append(vecPath, fmt.Sprint(v))
+	}
+	scanPath := strings.Join(vecPath, INDEX_PATH_SEP)
+	if _, indexed := src.indexPaths[scanPath]; !indexed {
+		return src.ApproxDocCount()
+	}
+	hash := StrHash(fmt.Sprint(lookupValue))
+	return src.hts[hash%src.db.numParts][scanPath].ApproxBucketCount(hash)
+}
+
+func estimateIntRange(intFrom interface{}, expr map[string]interface{}, src *Col) int {
+	from, _ := intFrom.(float64)
+	to, hasTo := expr["int-to"].(float64)
+	if !hasTo {
+		to, hasTo = expr["int to"].(float64)
+	}
+	if !hasTo {
+		return src.ApproxDocCount()
+	}
+	keyRange := int(to) - int(from)
+	if keyRange < 0 {
+		keyRange = -keyRange
+	}
+	keyRange++
+	avgPostingsPerKey := src.ApproxDocCount() / 1000
+	if avgPostingsPerKey < 1 {
+		avgPostingsPerKey = 1
+	}
+	return keyRange * avgPostingsPerKey
+}
+
+// estimateLimited is used by operators whose cost is dominated by an explicit `limit`, when given.
+func estimateLimited(expr map[string]interface{}, src *Col) int {
+	if limit, ok := expr["limit"].(float64); ok && limit > 0 {
+		return int(limit)
+	}
+	return src.ApproxDocCount()
+}
+
+func estimateUnion(subExprs interface{}, src *Col) int {
+	subExprVecs, ok := subExprs.([]interface{})
+	if !ok {
+		return src.ApproxDocCount()
+	}
+	sum := 0
+	for _, sub := range subExprVecs {
+		sum += EstimateCardinality(sub, src)
+	}
+	return sum
+}
+
+func estimateIntersect(subExprs interface{}, src *Col) int {
+	subExprVecs, ok := subExprs.([]interface{})
+	if !ok || len(subExprVecs) == 0 {
+		return src.ApproxDocCount()
+	}
+	min := EstimateCardinality(subExprVecs[0], src)
+	for _, sub := range subExprVecs[1:] {
+		if est := EstimateCardinality(sub, src); est < min {
+			min = est
+		}
+	}
+	return min
+}
+
+// orderBySelectivity returns subExprVecs sorted by ascending EstimateCardinality, cheapest first.
+func orderBySelectivity(subExprVecs []interface{}, src *Col) []interface{} {
+	ordered := make([]interface{}, len(subExprVecs))
+	copy(ordered, subExprVecs)
+	sort.SliceStable(ordered, func(i, j int) bool {
+		return EstimateCardinality(ordered[i], src) < EstimateCardinality(ordered[j], src)
+	})
+	return ordered
+}
+
+// PlannedStep describes one sub-query's position in a chosen evaluation order, returned by
+// PlanQuery so callers (and the benchmark) can inspect the planner's decisions without running it.
+type PlannedStep struct {
+	SubQuery interface{}
+	Estimate int
+}
+
+// QueryPlan is the EXPLAIN-style result of PlanQuery: the order Intersect/Complement would
+// evaluate their sub-queries in, cheapest first, along with each sub-query's estimated cardinality.
+type QueryPlan struct {
+	Op    string
+	Steps []PlannedStep
+}
+
+// PlanQuery inspects an "n" (intersect) or "c" (complement) query and returns the order its
+// sub-queries would be evaluated in along with their estimated cardinalities, without actually
+// running the query. Any other query shape returns a single-step plan with its own estimate.
+func PlanQuery(q interface{}, src *Col) (*QueryPlan, error) {
+	expr, ok := q.(map[string]interface{})
+	if !ok {
+		return &QueryPlan{Op: "eval", Steps: []PlannedStep{{SubQuery: q, Estimate: EstimateCardinality(q, src)}}}, nil
+	}
+	op, subExprs := "", interface{}(nil)
+	if sub, intersect := expr["n"]; intersect {
+		op, subExprs = "n", sub
+	} else if sub, complement := expr["c"]; complement {
+		op, subExprs = "c", sub
+	} else {
+		return &QueryPlan{Op: "eval", Steps: []PlannedStep{{SubQuery: q, Estimate: EstimateCardinality(q, src)}}}, nil
+	}
+	subExprVecs, ok := subExprs.([]interface{})
+	if !ok {
+		return nil, fmt.Errorf("expecting a vector of sub-queries, but %v given", subExprs)
+	}
+	ordered := orderBySelectivity(subExprVecs, src)
+	steps := make([]PlannedStep, len(ordered))
+	for i, sub := range ordered {
+		steps[i] = PlannedStep{SubQuery: sub, Estimate: EstimateCardinality(sub, src)}
+	}
+	return &QueryPlan{Op: op, Steps: steps}, nil
+}
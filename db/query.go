@@ -5,6 +5,7 @@ import (
 	"errors"
 	"fmt"
 	"github.com/HouzuoGuo/tiedot/tdlog"
+	"regexp"
 	"strconv"
 	"strings"
 )
@@ -124,38 +125,210 @@ func PathExistence(hasPath interface{}, expr map[string]interface{}, src *Col, r
 	return nil
 }
 
-// Calculate intersection of sub-query results.
+// Calculate intersection of sub-query results. Sub-queries are planned cheapest-first (see
+// planner.go) so the working set is seeded small and only ever shrinks. The first sub-query is
+// evaluated into a fresh local map rather than the caller's *result, since *result may already
+// hold IDs contributed by an earlier, unrelated sibling in an enclosing union - merging straight
+// into it would make this intersect wrongly prune those sibling matches. Once seeded, every
+// remaining sub-query is only *probed* against the working set's documents (matchesQuery) rather
+// than independently evaluated and materialized in full - so a selective "eq" seeding a handful of
+// IDs keeps a subsequent million-row "int-from"/"int-to" range from ever touching more than those
+// few documents. The exceptions are "search", "str-from"/"from" and "c": matchesQuery answers those
+// by evaluating the whole sub-query for each probed document. Only the final intersected set is
+// merged into *result.
 func Intersect(subExprs interface{}, src *Col, result *map[int]struct{}) (err error) {
 	if subExprVecs, ok := subExprs.([]interface{}); ok {
-		first := true
-		for _, subExpr := range subExprVecs {
-			subResult := make(map[int]struct{})
-			intersection := make(map[int]struct{})
-			if err = EvalQuery(subExpr, src, &subResult); err != nil {
-				return
+		ordered := orderBySelectivity(subExprVecs, src)
+		seeded := make(map[int]struct{})
+		for i, subExpr := range ordered {
+			if i == 0 {
+				if err = EvalQuery(subExpr, src, &seeded); err != nil {
+					return
+				}
+				continue
 			}
-			if first {
-				*result = subResult
-				first = false
-			} else {
-				for k, _ := range subResult {
-					if _, inBoth := (*result)[k]; inBoth {
-						intersection[k] = struct{}{}
+			if len(seeded) == 0 {
+				break
+			}
+			for id := range seeded {
+				doc, readErr := src.Read(id)
+				matched := false
+				if readErr == nil {
+					matched, err = matchesQuery(subExpr, src, id, doc)
+					if err != nil {
+						return
 					}
 				}
-				*result = intersection
+				if !matched {
+					delete(seeded, id)
+				}
 			}
 		}
+		for id := range seeded {
+			(*result)[id] = struct{}{}
+		}
 	} else {
 		return errors.New(fmt.Sprintf("Expecting a vector of sub-queries, but %v given", subExprs))
 	}
 	return
 }
 
-// Calculate complement of sub-query results.
+// matchesQuery evaluates expr against a single already-read document; it is how Intersect probes
+// its working set instead of re-materializing every sub-query in full. It mirrors EvalQuery's
+// operator set but answers "does this one document match" rather than "which documents match".
+// Union, PK ID, "all", "eq", "has", "n", integer range and "re" are checked against the document
+// alone without touching an index; every other operator is evaluated in full via EvalQuery.
+func matchesQuery(q interface{}, src *Col, id int, doc map[string]interface{}) (matched bool, err error) {
+	switch expr := q.(type) {
+	case []interface{}: // union - matches if any sub-query matches
+		for _, subExpr := range expr {
+			if matched, err = matchesQuery(subExpr, src, id, doc); err != nil || matched {
+				return
+			}
+		}
+		return false, nil
+	case string:
+		if expr == "all" {
+			return true, nil
+		}
+		docID, parseErr := strconv.ParseInt(expr, 10, 64)
+		if parseErr != nil {
+			return false, errors.New(fmt.Sprintf("%s is not a document PK ID", expr))
+		}
+		return int(docID) == id, nil
+	case map[string]interface{}:
+		if lookupValue, lookup := expr["eq"]; lookup {
+			vecPath, pathErr := vectorPath(expr, "in")
+			if pathErr != nil {
+				return false, pathErr
+			}
+			lookupStrValue := fmt.Sprint(lookupValue)
+			for _, v := range GetIn(doc, vecPath) {
+				if fmt.Sprint(v) == lookupStrValue {
+					return true, nil
+				}
+			}
+			return false, nil
+		} else if _, exist := expr["has"]; exist {
+			vecPath, pathErr := vectorPath(expr, "has")
+			if pathErr != nil {
+				return false, pathErr
+			}
+			return len(GetIn(doc, vecPath)) > 0, nil
+		} else if subExprs, intersect := expr["n"]; intersect {
+			subExprVecs, ok := subExprs.([]interface{})
+			if !ok {
+				return false, errors.New(fmt.Sprintf("Expecting a vector of sub-queries, but %v given", subExprs))
+			}
+			for _, subExpr := range subExprVecs {
+				if matched, err = matchesQuery(subExpr, src, id, doc); err != nil || !matched {
+					return
+				}
+			}
+			return true, nil
+		} else if intFrom, htRange := expr["int-from"]; htRange {
+			return matchesIntRange(intFrom, expr, doc)
+		} else if intFrom, htRange := expr["int from"]; htRange {
+			return matchesIntRange(intFrom, expr, doc)
+		} else if pattern, regex := expr["re"]; regex {
+			return matchesRegex(pattern, expr, doc)
+		}
+		// "search" and "str-from"/"from"/"c" (and anything else) fall back to full evaluation
+		// instead of a doc-local check: "search" ranks by BM25 and keeps only its own top `limit`
+		// hits, "str-from"/"from" orders and limits its own matches, and "c" (complement) can
+		// surface documents outside the probed working set entirely - none of those can be
+		// answered by looking at one document in isolation. EvalQuery(..., &subResult) followed by
+		// a membership test reproduces the operator's real behavior, just scoped to this one ID.
+		subResult := make(map[int]struct{})
+		if err = EvalQuery(q, src, &subResult); err != nil {
+			return
+		}
+		_, matched = subResult[id]
+		return matched, nil
+	}
+	return false, nil
+}
+
+// vectorPath extracts expr[key] as a []string path, the same way every query operator in this
+// file does for its "in" parameter.
+func vectorPath(expr map[string]interface{}, key string) ([]string, error) {
+	path, hasPath := expr[key]
+	if !hasPath {
+		return nil, errors.New(fmt.Sprintf("Missing path `%s`", key))
+	}
+	vecPathInterface, ok := path.([]interface{})
+	if !ok {
+		return nil, errors.New(fmt.Sprintf("Expecting vector path `%s`, but %v given", key, path))
+	}
+	vecPath := make([]string, 0, len(vecPathInterface))
+	for _, v := range vecPathInterface {
+		vecPath = append(vecPath, fmt.Sprint(v))
+	}
+	return vecPath, nil
+}
+
+func matchesIntRange(intFrom interface{}, expr map[string]interface{}, doc map[string]interface{}) (bool, error) {
+	vecPath, err := vectorPath(expr, "in")
+	if err != nil {
+		return false, err
+	}
+	from, ok := intFrom.(float64)
+	if !ok {
+		return false, errors.New(fmt.Sprintf("Expecting `int-from` as an integer, but %v given", intFrom))
+	}
+	toRaw, hasTo := expr["int-to"]
+	if !hasTo {
+		toRaw, hasTo = expr["int to"]
+	}
+	if !hasTo {
+		return false, errors.New("Missing `int-to`")
+	}
+	to, ok := toRaw.(float64)
+	if !ok {
+		return false, errors.New(fmt.Sprintf("Expecting `int-to` as an integer, but %v given", toRaw))
+	}
+	lo, hi := from, to
+	if hi < lo {
+		lo, hi = hi, lo
+	}
+	for _, v := range GetIn(doc, vecPath) {
+		if f, ok := v.(float64); ok && f >= lo && f <= hi {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func matchesRegex(pattern interface{}, expr map[string]interface{}, doc map[string]interface{}) (bool, error) {
+	patternStr, ok := pattern.(string)
+	if !ok {
+		return false, fmt.Errorf("expecting `re` value to be a string, but %v given", pattern)
+	}
+	if caseInsensitive, _ := expr["case-insensitive"].(bool); caseInsensitive {
+		patternStr = "(?i)" + patternStr
+	}
+	re, err := regexp.Compile(patternStr)
+	if err != nil {
+		return false, fmt.Errorf("invalid regex %v: %v", pattern, err)
+	}
+	vecPath, err := vectorPath(expr, "in")
+	if err != nil {
+		return false, err
+	}
+	for _, v := range GetIn(doc, vecPath) {
+		if re.MatchString(textValueToString(v)) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// Calculate complement of sub-query results, planning the positive side cheapest-first the same
+// way Intersect does.
 func Complement(subExprs interface{}, src *Col, result *map[int]struct{}) (err error) {
 	if subExprVecs, ok := subExprs.([]interface{}); ok {
-		for _, subExpr := range subExprVecs {
+		ordered := orderBySelectivity(subExprVecs, src)
+		for _, subExpr := range ordered {
 			subResult := make(map[int]struct{})
 			complement := make(map[int]struct{})
 			if err = EvalQuery(subExpr, src, &subResult); err != nil {
@@ -302,12 +475,17 @@ func EvalQuery(q interface{}, src *Col, result *map[int]struct{}) (err error) {
 			return IntRange(intFrom, expr, src, result)
 		} else if intFrom, htRange := expr["int from"]; htRange { // "int from, "int to" - integer range query - same as above, just without dash
 			return IntRange(intFrom, expr, src, result)
+		} else if queryText, search := expr["search"]; search { // search - full-text search via inverted index + BM25
+			return TextSearch(queryText, expr, src, result)
+		} else if fromValue, strRange := expr["str-from"]; strRange { // str-from, str-to - ordered string range query
+			return StrRange(fromValue, expr, src, result)
+		} else if fromValue, strRange := expr["from"]; strRange { // from, to - generic alias of str-from/str-to
+			return StrRange(fromValue, expr, src, result)
+		} else if pattern, regex := expr["re"]; regex { // re - regex match, trigram-accelerated when indexed
+			return RegexMatch(pattern, expr, src, result)
 		} else {
 			return errors.New(fmt.Sprintf("Query %v does not contain any operation (lookup/union/etc)", expr))
 		}
 	}
 	return nil
 }
-
-// TODO: How to bring back regex matcher?
-// TODO: How to bring back JSON parameterized query?
@@ -0,0 +1,102 @@
+package db
+
+import "testing"
+
+func TestIntersectProbesRatherThanMaterializes(t *testing.T) {
+	tmpDB, _ := OpenDB("")
+	col, _ := OpenCol(tmpDB, "test")
+	col.Index([]string{"tag"})
+	col.Index([]string{"rank"})
+
+	match, _ := col.Insert(map[string]interface{}{"tag": "wanted", "rank": 5})
+	col.Insert(map[string]interface{}{"tag": "wanted", "rank": 999})
+	for i := 0; i < 50; i++ {
+		if _, err := col.Insert(map[string]interface{}{"tag": "noise", "rank": i}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	result := make(map[int]struct{})
+	q := map[string]interface{}{"n": []interface{}{
+		map[string]interface{}{"eq": "wanted", "in": []interface{}{"tag"}},
+		map[string]interface{}{"int-from": float64(0), "int-to": float64(10), "in": []interface{}{"rank"}},
+	}}
+	if err := EvalQuery(q, col, &result); err != nil {
+		t.Fatal(err)
+	}
+	if len(result) != 1 {
+		t.Fatalf("expected exactly one document to satisfy both conditions, got %v", result)
+	}
+	if _, ok := result[match]; !ok {
+		t.Errorf("expected document %d in result %v", match, result)
+	}
+}
+
+func TestIntersectDoesNotClobberSiblingUnionMatches(t *testing.T) {
+	tmpDB, _ := OpenDB("")
+	col, _ := OpenCol(tmpDB, "test")
+	col.Index([]string{"tag"})
+	col.Index([]string{"tag2"})
+	col.Index([]string{"rank"})
+
+	onlyFirstBranch, _ := col.Insert(map[string]interface{}{"tag": "alpha"})
+	bothBranches, _ := col.Insert(map[string]interface{}{"tag": "alpha", "tag2": "beta", "rank": 1})
+
+	result := make(map[int]struct{})
+	q := []interface{}{
+		map[string]interface{}{"eq": "alpha", "in": []interface{}{"tag"}},
+		map[string]interface{}{"n": []interface{}{
+			map[string]interface{}{"eq": "beta", "in": []interface{}{"tag2"}},
+			map[string]interface{}{"int-from": float64(0), "int-to": float64(5), "in": []interface{}{"rank"}},
+		}},
+	}
+	if err := EvalQuery(q, col, &result); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := result[onlyFirstBranch]; !ok {
+		t.Errorf("document %d matched the union's first branch and must survive the second branch's intersect untouched, got %v", onlyFirstBranch, result)
+	}
+	if _, ok := result[bothBranches]; !ok {
+		t.Errorf("expected document %d (matches both union branches) in result %v", bothBranches, result)
+	}
+}
+
+func TestIntersectHonorsSearchOwnLimit(t *testing.T) {
+	tmpDB, _ := OpenDB("")
+	col, _ := OpenCol(tmpDB, "test")
+	col.Index([]string{"tag"})
+	col.IndexText([]string{"body"}, "standard")
+
+	// Both documents are tagged "rare", but only one may survive "search"'s own top-1 ranking:
+	// repeating "quick" gives it a much higher BM25 score than the other's single occurrence.
+	topHit, _ := col.Insert(map[string]interface{}{"tag": "rare", "body": "quick quick quick fox"})
+	secondHit, _ := col.Insert(map[string]interface{}{"tag": "rare", "body": "the fox was quick"})
+
+	// Exercise matchesQuery directly: regardless of which operand the planner happens to put
+	// first in an "n", probing "search" against a document must respect its own BM25 ranking and
+	// limit, not just whether the query terms appear anywhere in the field.
+	topDoc, _ := col.Read(topHit)
+	secondDoc, _ := col.Read(secondHit)
+	searchExpr := map[string]interface{}{"search": "quick", "in": []interface{}{"body"}, "limit": float64(1)}
+	if matched, err := matchesQuery(searchExpr, col, topHit, topDoc); err != nil || !matched {
+		t.Errorf("expected the top-ranked document %d to match search's own limit:1, matched=%v err=%v", topHit, matched, err)
+	}
+	if matched, err := matchesQuery(searchExpr, col, secondHit, secondDoc); err != nil || matched {
+		t.Errorf("document %d falls outside search's own top-1 hit and must not match, matched=%v err=%v", secondHit, matched, err)
+	}
+
+	result := make(map[int]struct{})
+	q := map[string]interface{}{"n": []interface{}{
+		map[string]interface{}{"eq": "rare", "in": []interface{}{"tag"}},
+		searchExpr,
+	}}
+	if err := EvalQuery(q, col, &result); err != nil {
+		t.Fatal(err)
+	}
+	if len(result) != 1 {
+		t.Fatalf("expected search's own limit:1 to cap the intersect at one document, got %v", result)
+	}
+	if _, ok := result[topHit]; !ok {
+		t.Errorf("expected the top-ranked document %d in result %v", topHit, result)
+	}
+}
@@ -0,0 +1,259 @@
+/* Inverted index for full-text search, maintained per-partition next to the hashtable indexes. */
+package db
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// BM25 tuning constants; see Robertson & Zaragoza, "The Probabilistic Relevance Framework".
+const (
+	DefaultBM25K1 = 1.2
+	DefaultBM25B  = 0.75
+)
+
+// posting records how many times a term occurs in one document.
+type posting struct {
+	docID int
+	freq  int
+}
+
+// TextIndex is one partition's inverted index for a single indexed field path: term -> postings.
+type TextIndex struct {
+	Lock     sync.RWMutex
+	Analyzer *Analyzer
+	postings map[string][]posting
+	docLen   map[int]int // number of analyzed terms per document, used by BM25 length normalization
+	totalLen int
+	numDocs  int
+}
+
+// NewTextIndex creates an empty text index driven by the given analyzer.
+func NewTextIndex(analyzer *Analyzer) *TextIndex {
+	return &TextIndex{
+		Analyzer: analyzer,
+		postings: make(map[string][]posting),
+		docLen:   make(map[int]int),
+	}
+}
+
+// IndexDocument analyzes text and adds its terms to the posting lists under docID.
+func (ti *TextIndex) IndexDocument(docID int, text string) {
+	terms := ti.Analyzer.Analyze(text)
+	if len(terms) == 0 {
+		return
+	}
+	freq := make(map[string]int, len(terms))
+	for _, term := range terms {
+		freq[term]++
+	}
+	ti.Lock.Lock()
+	defer ti.Lock.Unlock()
+	for term, n := range freq {
+		ti.postings[term] = append(ti.postings[term], posting{docID: docID, freq: n})
+	}
+	ti.docLen[docID] = len(terms)
+	ti.totalLen += len(terms)
+	ti.numDocs++
+}
+
+// RemoveDocument deletes docID from every posting list it appears in; called ahead of Update/Delete.
+func (ti *TextIndex) RemoveDocument(docID int) {
+	ti.Lock.Lock()
+	defer ti.Lock.Unlock()
+	length, exists := ti.docLen[docID]
+	if !exists {
+		return
+	}
+	for term, list := range ti.postings {
+		for i, p := range list {
+			if p.docID == docID {
+				ti.postings[term] = append(list[:i], list[i+1:]...)
+				break
+			}
+		}
+		if len(ti.postings[term]) == 0 {
+			delete(ti.postings, term)
+		}
+	}
+	delete(ti.docLen, docID)
+	ti.totalLen -= length
+	ti.numDocs--
+}
+
+// avgDocLen returns the average analyzed document length, used as BM25's avgdl.
+func (ti *TextIndex) avgDocLen() float64 {
+	if ti.numDocs == 0 {
+		return 0
+	}
+	return float64(ti.totalLen) / float64(ti.numDocs)
+}
+
+// idf is BM25's inverse document frequency term for a query term appearing in df documents.
+func (ti *TextIndex) idf(df int) float64 {
+	if df == 0 {
+		return 0
+	}
+	n := float64(ti.numDocs)
+	return math.Log(1 + (n-float64(df)+0.5)/(float64(df)+0.5))
+}
+
+// scoredDoc is one ranked hit from searchScored.
+type scoredDoc struct {
+	docID int
+	score float64
+}
+
+// searchScored ranks this partition's documents against a pre-analyzed set of query terms using
+// BM25 and returns every match, unsorted and untruncated; callers merge scores across partitions
+// before applying a global limit (a per-partition limit would only ever return the top `limit`
+// hits of each partition, not the top `limit` hits overall).
+func (ti *TextIndex) searchScored(queryTerms []string, k1, b float64) []scoredDoc {
+	ti.Lock.RLock()
+	defer ti.Lock.RUnlock()
+	avgdl := ti.avgDocLen()
+	scores := make(map[int]float64)
+	for _, term := range queryTerms {
+		list, found := ti.postings[term]
+		if !found {
+			continue
+		}
+		idf := ti.idf(len(list))
+		for _, p := range list {
+			dl := float64(ti.docLen[p.docID])
+			tf := float64(p.freq)
+			norm := tf * (k1 + 1) / (tf + k1*(1-b+b*dl/avgdl))
+			scores[p.docID] += idf * norm
+		}
+	}
+	ranked := make([]scoredDoc, 0, len(scores))
+	for id, score := range scores {
+		ranked = append(ranked, scoredDoc{docID: id, score: score})
+	}
+	return ranked
+}
+
+// textValueToString flattens a field value (string, or slice of strings) into one analyzable blob.
+func textValueToString(v interface{}) string {
+	switch val := v.(type) {
+	case string:
+		return val
+	case []interface{}:
+		parts := make([]string, 0, len(val))
+		for _, elem := range val {
+			parts = append(parts, textValueToString(elem))
+		}
+		return strings.Join(parts, " ")
+	default:
+		return ""
+	}
+}
+
+// IndexText builds a full-text index over `path` using the named analyzer (see GetAnalyzer),
+// paralleling Col.Index for hashtable indexes. One TextIndex is kept per partition, and - like
+// hts - the indexes for different paths are kept fully separate: a term found only via one path
+// must never surface a hit for a "search" query made against a different path.
+func (col *Col) IndexText(path []string, analyzerName string) error {
+	analyzer := GetAnalyzer(analyzerName)
+	if analyzer == nil {
+		return fmt.Errorf("unknown analyzer %s, register it first with RegisterAnalyzer", analyzerName)
+	}
+	jointPath := strings.Join(path, INDEX_PATH_SEP)
+	perPartition := make([]*TextIndex, col.db.numParts)
+	for i := range perPartition {
+		perPartition[i] = NewTextIndex(analyzer)
+	}
+	col.textIndexes[jointPath] = perPartition
+	col.textIndexPaths[jointPath] = path
+	col.ForEachDoc(func(id int, _ []byte) bool {
+		if doc, err := col.Read(id); err == nil {
+			col.indexTextPath(id, jointPath, path, doc)
+		}
+		return true
+	})
+	return nil
+}
+
+func (col *Col) indexTextPath(id int, jointPath string, path []string, doc map[string]interface{}) {
+	for _, v := range GetIn(doc, path) {
+		if text := textValueToString(v); text != "" {
+			col.textIndexes[jointPath][id%col.db.numParts].IndexDocument(id, text)
+		}
+	}
+}
+
+// indexTextDoc analyzes and indexes every text-indexed path of a freshly inserted/updated document
+// into its own TextIndex; Insert and Update call this, Delete calls removeTextDoc beforehand.
+func (col *Col) indexTextDoc(id int, doc map[string]interface{}) {
+	for jointPath, path := range col.textIndexPaths {
+		col.indexTextPath(id, jointPath, path, doc)
+	}
+}
+
+// removeTextDoc removes a document from every text index ahead of Update/Delete.
+func (col *Col) removeTextDoc(id int, doc map[string]interface{}) {
+	for jointPath := range col.textIndexPaths {
+		col.textIndexes[jointPath][id%col.db.numParts].RemoveDocument(id)
+	}
+}
+
+// TextSearch implements the "search" query operator: analyze the query string with the same
+// analyzer used at index time, rank candidates with BM25 and put the top `limit` IDs in result.
+func TextSearch(queryText interface{}, expr map[string]interface{}, src *Col, result *map[int]struct{}) (err error) {
+	text, ok := queryText.(string)
+	if !ok {
+		return fmt.Errorf("expecting `search` value to be a string, but %v given", queryText)
+	}
+	path, hasPath := expr["in"]
+	if !hasPath {
+		return errors.New("missing search path `in`")
+	}
+	vecPath := make([]string, 0)
+	if vecPathInterface, ok := path.([]interface{}); ok {
+		for _, v := range vecPathInterface {
+			vecPath = append(vecPath, fmt.Sprint(v))
+		}
+	} else {
+		return fmt.Errorf("expecting vector lookup path `in`, but %v given", path)
+	}
+	jointPath := strings.Join(vecPath, INDEX_PATH_SEP)
+	partitions, indexed := src.textIndexes[jointPath]
+	if !indexed {
+		return fmt.Errorf("please IndexText %v and retry query %v", vecPath, expr)
+	}
+	intLimit := 0
+	if limit, hasLimit := expr["limit"]; hasLimit {
+		floatLimit, ok := limit.(float64)
+		if !ok {
+			return fmt.Errorf("expecting `limit` as a number, but %v given", limit)
+		}
+		intLimit = int(floatLimit)
+	}
+	k1, b := DefaultBM25K1, DefaultBM25B
+	if v, ok := expr["k1"].(float64); ok {
+		k1 = v
+	}
+	if v, ok := expr["b"].(float64); ok {
+		b = v
+	}
+	analyzer := partitions[0].Analyzer
+	queryTerms := analyzer.Analyze(text)
+	// Merge every partition's scores before ranking, so the global top `limit` hits are returned
+	// rather than up to `limit` hits from each partition.
+	var ranked []scoredDoc
+	for _, ti := range partitions {
+		ranked = append(ranked, ti.searchScored(queryTerms, k1, b)...)
+	}
+	sort.Slice(ranked, func(i, j int) bool { return ranked[i].score > ranked[j].score })
+	if intLimit > 0 && len(ranked) > intLimit {
+		ranked = ranked[:intLimit]
+	}
+	for _, r := range ranked {
+		(*result)[r.docID] = struct{}{}
+	}
+	return nil
+}
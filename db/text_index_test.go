@@ -0,0 +1,47 @@
+package db
+
+import "testing"
+
+func TestTextSearchScopedByPath(t *testing.T) {
+	tmpDB, _ := OpenDB("")
+	col, _ := OpenCol(tmpDB, "test")
+	col.IndexText([]string{"title"}, "standard")
+	col.IndexText([]string{"body"}, "standard")
+
+	idTitleOnly, _ := col.Insert(map[string]interface{}{"title": "quick fox", "body": "nothing relevant here"})
+	idBodyOnly, _ := col.Insert(map[string]interface{}{"title": "irrelevant", "body": "the quick brown fox jumps"})
+
+	result := make(map[int]struct{})
+	q := map[string]interface{}{"search": "quick", "in": []interface{}{"body"}}
+	if err := EvalQuery(q, col, &result); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := result[idBodyOnly]; !ok {
+		t.Errorf("expected document %d (matches via body) in result %v", idBodyOnly, result)
+	}
+	if _, ok := result[idTitleOnly]; ok {
+		t.Errorf("document %d only matches via title, must not appear when searching body", idTitleOnly)
+	}
+}
+
+func TestTextSearchLimitIsGlobal(t *testing.T) {
+	tmpDB, _ := OpenDB("")
+	col, _ := OpenCol(tmpDB, "test")
+	col.IndexText([]string{"body"}, "standard")
+
+	// Insert enough matching documents to spread across every partition.
+	for i := 0; i < numPartsDefault*3; i++ {
+		if _, err := col.Insert(map[string]interface{}{"body": "quick fox"}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	result := make(map[int]struct{})
+	q := map[string]interface{}{"search": "quick", "in": []interface{}{"body"}, "limit": float64(2)}
+	if err := EvalQuery(q, col, &result); err != nil {
+		t.Fatal(err)
+	}
+	if len(result) > 2 {
+		t.Errorf("expected at most 2 results under a global limit, got %d: %v", len(result), result)
+	}
+}
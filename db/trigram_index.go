@@ -0,0 +1,322 @@
+/* Trigram index for regex acceleration, built using the same required-trigram extraction trick as
+the codesearch tool: a regex is reduced to a boolean AND-of-OR expression over 3-rune substrings
+that any matching string must contain, which narrows a full scan down to a small candidate set. */
+package db
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"regexp/syntax"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// TrigramIndex is one partition's map from a 3-rune key to the sorted doc IDs whose indexed text
+// contains that trigram, plus enough bookkeeping to remove a document again on Update/Delete.
+type TrigramIndex struct {
+	Lock     sync.RWMutex
+	postings map[string][]int
+	docGrams map[int]map[string]struct{}
+}
+
+// NewTrigramIndex creates an empty trigram index.
+func NewTrigramIndex() *TrigramIndex {
+	return &TrigramIndex{
+		postings: make(map[string][]int),
+		docGrams: make(map[int]map[string]struct{}),
+	}
+}
+
+// trigramsOf returns every distinct 3-rune substring of s, lower-cased so the index is always
+// case-insensitive (case-sensitive matching is still done against the real document value).
+func trigramsOf(s string) map[string]struct{} {
+	s = strings.ToLower(s)
+	runes := []rune(s)
+	grams := make(map[string]struct{})
+	for i := 0; i+3 <= len(runes); i++ {
+		grams[string(runes[i:i+3])] = struct{}{}
+	}
+	return grams
+}
+
+// Add indexes docID under every trigram found in text.
+func (tg *TrigramIndex) Add(docID int, text string) {
+	grams := trigramsOf(text)
+	if len(grams) == 0 {
+		return
+	}
+	tg.Lock.Lock()
+	defer tg.Lock.Unlock()
+	for g := range grams {
+		list := tg.postings[g]
+		i := sort.SearchInts(list, docID)
+		if i == len(list) || list[i] != docID {
+			list = append(list, 0)
+			copy(list[i+1:], list[i:])
+			list[i] = docID
+			tg.postings[g] = list
+		}
+	}
+	tg.docGrams[docID] = grams
+}
+
+// Remove strips docID out of every trigram posting list it was added under.
+func (tg *TrigramIndex) Remove(docID int) {
+	tg.Lock.Lock()
+	defer tg.Lock.Unlock()
+	grams, exists := tg.docGrams[docID]
+	if !exists {
+		return
+	}
+	for g := range grams {
+		list := tg.postings[g]
+		i := sort.SearchInts(list, docID)
+		if i < len(list) && list[i] == docID {
+			tg.postings[g] = append(list[:i], list[i+1:]...)
+		}
+		if len(tg.postings[g]) == 0 {
+			delete(tg.postings, g)
+		}
+	}
+	delete(tg.docGrams, docID)
+}
+
+// Postings returns the sorted doc IDs containing trigram g.
+func (tg *TrigramIndex) Postings(g string) []int {
+	tg.Lock.RLock()
+	defer tg.Lock.RUnlock()
+	return tg.postings[g]
+}
+
+// IndexTrigram builds a trigram index over `path`, enabling accelerated regex search via the "re"
+// query operator; without it, "re" falls back to a full unindexed scan.
+func (col *Col) IndexTrigram(path []string) error {
+	jointPath := strings.Join(path, INDEX_PATH_SEP)
+	perPartition := make([]*TrigramIndex, col.db.numParts)
+	for i := range perPartition {
+		perPartition[i] = NewTrigramIndex()
+	}
+	col.trigramIndexes[jointPath] = perPartition
+	col.trigramIndexPaths[jointPath] = path
+	col.ForEachDoc(func(id int, _ []byte) bool {
+		if doc, err := col.Read(id); err == nil {
+			col.addTrigramPath(id, jointPath, path, doc)
+		}
+		return true
+	})
+	return nil
+}
+
+func (col *Col) addTrigramPath(id int, jointPath string, path []string, doc map[string]interface{}) {
+	for _, v := range GetIn(doc, path) {
+		if text := textValueToString(v); text != "" {
+			col.trigramIndexes[jointPath][id%col.db.numParts].Add(id, text)
+		}
+	}
+}
+
+// addTrigramDoc indexes a freshly inserted/updated document's fields into every trigram index,
+// each keyed by its own path; Insert and Update call this, Delete calls removeTrigramDoc first.
+func (col *Col) addTrigramDoc(id int, doc map[string]interface{}) {
+	for jointPath, path := range col.trigramIndexPaths {
+		col.addTrigramPath(id, jointPath, path, doc)
+	}
+}
+
+// removeTrigramDoc removes a document from every trigram index ahead of Update/Delete.
+func (col *Col) removeTrigramDoc(id int, doc map[string]interface{}) {
+	for jointPath := range col.trigramIndexPaths {
+		col.trigramIndexes[jointPath][id%col.db.numParts].Remove(id)
+	}
+}
+
+// trigramQuery is a small boolean expression over required trigrams: Or lists alternative
+// trigrams of which at least one must be present, And lists sub-expressions which all must hold.
+type trigramQuery struct {
+	or  []string
+	and []*trigramQuery
+}
+
+// requiredTrigrams reduces a parsed regex to the set of trigrams any matching string must contain,
+// mirroring codesearch's approach at a much smaller scale: only literal runs (and concatenations
+// and alternations built purely out of them) yield a requirement, everything else is treated as
+// "no requirement" so the search always falls back to verifying candidates with the real regexp.
+func requiredTrigrams(re *syntax.Regexp) *trigramQuery {
+	switch re.Op {
+	case syntax.OpLiteral:
+		s := string(re.Rune)
+		if len(re.Rune) < 3 {
+			return nil
+		}
+		q := &trigramQuery{}
+		for g := range trigramsOf(s) {
+			q.and = append(q.and, &trigramQuery{or: []string{g}})
+		}
+		return q
+	case syntax.OpCapture, syntax.OpPlus:
+		return requiredTrigrams(re.Sub[0])
+	case syntax.OpConcat:
+		return concatTrigrams(mergeLiterals(re.Sub))
+	case syntax.OpAlternate:
+		subReqs := make([]*trigramQuery, 0, len(re.Sub))
+		for _, sub := range re.Sub {
+			r := requiredTrigrams(sub)
+			if r == nil {
+				return nil // one branch has no requirement, so neither does the alternation
+			}
+			subReqs = append(subReqs, r)
+		}
+		// Each branch must hold one of its own trigrams; the whole alternation only guarantees
+		// that at least one branch's literal appears, i.e. an OR over one representative
+		// trigram per branch.
+		or := make([]string, 0, len(subReqs))
+		for _, r := range subReqs {
+			if len(r.and) == 0 || len(r.and[0].or) == 0 {
+				return nil
+			}
+			or = append(or, r.and[0].or...)
+		}
+		return &trigramQuery{or: or}
+	default:
+		return nil
+	}
+}
+
+// mergeLiterals coalesces adjacent OpLiteral nodes in a concatenation so trigrams spanning the
+// boundary between them (e.g. "foo"+"bar" -> "foobar") are still extracted.
+func mergeLiterals(subs []*syntax.Regexp) []*syntax.Regexp {
+	merged := make([]*syntax.Regexp, 0, len(subs))
+	for _, sub := range subs {
+		if sub.Op == syntax.OpLiteral && len(merged) > 0 && merged[len(merged)-1].Op == syntax.OpLiteral {
+			last := merged[len(merged)-1]
+			combined := *last
+			combined.Rune = append(append([]rune{}, last.Rune...), sub.Rune...)
+			merged[len(merged)-1] = &combined
+			continue
+		}
+		merged = append(merged, sub)
+	}
+	return merged
+}
+
+func concatTrigrams(subs []*syntax.Regexp) *trigramQuery {
+	q := &trigramQuery{}
+	for _, sub := range subs {
+		if r := requiredTrigrams(sub); r != nil {
+			q.and = append(q.and, r)
+		}
+	}
+	if len(q.and) == 0 {
+		return nil
+	}
+	return q
+}
+
+// candidateIDs evaluates a trigramQuery against the partition's trigram index, intersecting AND
+// groups and unioning OR alternatives, without ever materializing the full posting list universe.
+func (q *trigramQuery) candidateIDs(tg *TrigramIndex) map[int]struct{} {
+	if len(q.or) > 0 {
+		set := make(map[int]struct{})
+		for _, g := range q.or {
+			for _, id := range tg.Postings(g) {
+				set[id] = struct{}{}
+			}
+		}
+		return set
+	}
+	var result map[int]struct{}
+	for _, sub := range q.and {
+		ids := sub.candidateIDs(tg)
+		if result == nil {
+			result = ids
+			continue
+		}
+		for id := range result {
+			if _, ok := ids[id]; !ok {
+				delete(result, id)
+			}
+		}
+	}
+	if result == nil {
+		result = make(map[int]struct{})
+	}
+	return result
+}
+
+// RegexMatch implements the "re" query operator. It compiles the pattern once, then either
+// verifies candidates narrowed down by a trigram index, or falls back to scanning every document.
+func RegexMatch(pattern interface{}, expr map[string]interface{}, src *Col, result *map[int]struct{}) (err error) {
+	patternStr, ok := pattern.(string)
+	if !ok {
+		return fmt.Errorf("expecting `re` value to be a string, but %v given", pattern)
+	}
+	if caseInsensitive, _ := expr["case-insensitive"].(bool); caseInsensitive {
+		patternStr = "(?i)" + patternStr
+	}
+	re, err := regexp.Compile(patternStr)
+	if err != nil {
+		return fmt.Errorf("invalid regex %v: %v", pattern, err)
+	}
+	path, hasPath := expr["in"]
+	if !hasPath {
+		return errors.New("missing path `in`")
+	}
+	vecPath := make([]string, 0)
+	if vecPathInterface, ok := path.([]interface{}); ok {
+		for _, v := range vecPathInterface {
+			vecPath = append(vecPath, fmt.Sprint(v))
+		}
+	} else {
+		return fmt.Errorf("expecting vector path `in`, but %v given", path)
+	}
+	intLimit := 0
+	if limit, hasLimit := expr["limit"]; hasLimit {
+		floatLimit, ok := limit.(float64)
+		if !ok {
+			return fmt.Errorf("expecting `limit` as a number, but %v given", limit)
+		}
+		intLimit = int(floatLimit)
+	}
+	matchesDoc := func(doc map[string]interface{}) bool {
+		for _, v := range GetIn(doc, vecPath) {
+			if re.MatchString(textValueToString(v)) {
+				return true
+			}
+		}
+		return false
+	}
+	jointPath := strings.Join(vecPath, INDEX_PATH_SEP)
+	if partitions, trigramIndexed := src.trigramIndexes[jointPath]; trigramIndexed {
+		syn, parseErr := syntax.Parse(patternStr, syntax.Perl)
+		if parseErr == nil {
+			if req := requiredTrigrams(syn.Simplify()); req != nil {
+				counter := 0
+				for _, tg := range partitions {
+					for id := range req.candidateIDs(tg) {
+						if doc, err := src.Read(id); err == nil && matchesDoc(doc) {
+							(*result)[id] = struct{}{}
+							counter++
+							if intLimit > 0 && counter >= intLimit {
+								return nil
+							}
+						}
+					}
+				}
+				return nil
+			}
+		}
+		// No usable trigram requirement (e.g. ".*" or a bare character class) - fall through
+		// to the unindexed scan below rather than returning every document unverified.
+	}
+	counter := 0
+	src.ForEachDoc(func(id int, _ []byte) bool {
+		if doc, err := src.Read(id); err == nil && matchesDoc(doc) {
+			(*result)[id] = struct{}{}
+			counter++
+		}
+		return intLimit <= 0 || counter < intLimit
+	})
+	return nil
+}
@@ -0,0 +1,77 @@
+package db
+
+import "testing"
+
+func TestRegexMatchScopedByPath(t *testing.T) {
+	tmpDB, _ := OpenDB("")
+	col, _ := OpenCol(tmpDB, "test")
+	col.IndexTrigram([]string{"title"})
+	col.IndexTrigram([]string{"body"})
+
+	idTitleOnly, _ := col.Insert(map[string]interface{}{"title": "quick fox", "body": "nothing relevant here"})
+	idBodyOnly, _ := col.Insert(map[string]interface{}{"title": "irrelevant", "body": "the quick brown fox jumps"})
+
+	result := make(map[int]struct{})
+	q := map[string]interface{}{"re": "quick", "in": []interface{}{"body"}}
+	if err := EvalQuery(q, col, &result); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := result[idBodyOnly]; !ok {
+		t.Errorf("expected document %d (matches via body) in result %v", idBodyOnly, result)
+	}
+	if _, ok := result[idTitleOnly]; ok {
+		t.Errorf("document %d only matches via title, must not appear when matching against body", idTitleOnly)
+	}
+}
+
+func TestRegexMatchSeesInsertsAndDeletes(t *testing.T) {
+	tmpDB, _ := OpenDB("")
+	col, _ := OpenCol(tmpDB, "test")
+	col.IndexTrigram([]string{"body"})
+
+	id, _ := col.Insert(map[string]interface{}{"body": "the quick brown fox"})
+
+	result := make(map[int]struct{})
+	q := map[string]interface{}{"re": "quick", "in": []interface{}{"body"}}
+	if err := EvalQuery(q, col, &result); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := result[id]; !ok {
+		t.Errorf("expected freshly inserted document %d to be found via its trigram index", id)
+	}
+
+	if err := col.Delete(id); err != nil {
+		t.Fatal(err)
+	}
+	result = make(map[int]struct{})
+	if err := EvalQuery(q, col, &result); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := result[id]; ok {
+		t.Errorf("deleted document %d must no longer be found via its trigram index", id)
+	}
+}
+
+func TestRegexMatchUnindexedFallbackDecodesCompressedDocs(t *testing.T) {
+	tmpDB, _ := OpenDB("")
+	col, _ := OpenCol(tmpDB, "test")
+	if err := col.SetCompression("snappy"); err != nil {
+		t.Fatal(err)
+	}
+
+	// No IndexTrigram call, so RegexMatch must take the unindexed ForEachDoc fallback, which has
+	// to decode each document's codec header the same way col.Read does.
+	id, err := col.Insert(map[string]interface{}{"body": "the quick brown fox"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	result := make(map[int]struct{})
+	q := map[string]interface{}{"re": "quick", "in": []interface{}{"body"}}
+	if err := EvalQuery(q, col, &result); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := result[id]; !ok {
+		t.Errorf("expected document %d to be found by the unindexed regex fallback even under snappy compression, got %v", id, result)
+	}
+}
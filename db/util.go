@@ -0,0 +1,45 @@
+/* Small helpers shared by the query and indexing subsystems. */
+package db
+
+// INDEX_PATH_SEP joins a document path (e.g. []string{"a","b"}) into the flat string key used to
+// look up indexPaths, hts and the text/ordered/trigram index registries.
+const INDEX_PATH_SEP = ","
+
+// StrHash returns a cheap, stable hash of a string, used as the hashtable key for indexed values.
+func StrHash(s string) int {
+	hash := 0
+	for _, r := range s {
+		hash = hash*31 + int(r)
+	}
+	if hash < 0 {
+		hash = -hash
+	}
+	return hash
+}
+
+// GetIn fetches the value(s) located at path inside doc. A path component steps into a nested
+// map; if the current value is a slice, the remaining path is applied to every element and the
+// results are concatenated, so indexing ["tags"] on {"tags": ["a", "b"]} sees both "a" and "b".
+func GetIn(doc interface{}, path []string) (ret []interface{}) {
+	if len(path) == 0 {
+		if doc == nil {
+			return nil
+		}
+		return []interface{}{doc}
+	}
+	switch val := doc.(type) {
+	case map[string]interface{}:
+		child, exists := val[path[0]]
+		if !exists {
+			return nil
+		}
+		return GetIn(child, path[1:])
+	case []interface{}:
+		for _, elem := range val {
+			ret = append(ret, GetIn(elem, path)...)
+		}
+		return ret
+	default:
+		return nil
+	}
+}